@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// AccessRequest is a user's pending request to be granted a role.
+type AccessRequest struct {
+	ID        int       `json:"id"`
+	User      string    `json:"user"`
+	Role      string    `json:"role"`
+	Reason    string    `json:"reason,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// accessRequestStore holds access requests for display in /api/me.
+// Creating and resolving requests is not exposed over HTTP in this demo;
+// the store is seeded with sample data.
+type accessRequestStore struct {
+	mu       sync.Mutex
+	requests map[int]AccessRequest
+	nextID   int
+}
+
+func newAccessRequestStore() *accessRequestStore {
+	return &accessRequestStore{requests: make(map[int]AccessRequest), nextID: 1}
+}
+
+func (st *accessRequestStore) add(user, role, reason string) AccessRequest {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	req := AccessRequest{
+		ID:        st.nextID,
+		User:      user,
+		Role:      role,
+		Reason:    reason,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+	st.requests[req.ID] = req
+	st.nextID++
+	return req
+}
+
+// pending returns user's pending requests, oldest first.
+func (st *accessRequestStore) pending(user string) []AccessRequest {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	pending := []AccessRequest{}
+	for _, req := range st.requests {
+		if req.User == user && req.Status == "pending" {
+			pending = append(pending, req)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID < pending[j].ID
+	})
+	return pending
+}
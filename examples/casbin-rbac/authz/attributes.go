@@ -0,0 +1,41 @@
+package authz
+
+import (
+	"context"
+	"log"
+)
+
+// AttributeProvider supplies extra attributes about a request (tenant,
+// department, client IP, ...) that are attached to its audit event.
+type AttributeProvider interface {
+	Attributes(ctx context.Context, req Request) (map[string]string, error)
+}
+
+// AttributeProviderFunc adapts a function to the AttributeProvider interface.
+type AttributeProviderFunc func(ctx context.Context, req Request) (map[string]string, error)
+
+func (f AttributeProviderFunc) Attributes(ctx context.Context, req Request) (map[string]string, error) {
+	return f(ctx, req)
+}
+
+// collectAttributes merges the attributes of all providers; later
+// providers win on key conflicts. A failing provider is logged and
+// skipped so it cannot block authorization.
+func collectAttributes(ctx context.Context, providers []AttributeProvider, req Request) map[string]string {
+	if len(providers) == 0 {
+		return nil
+	}
+
+	attrs := make(map[string]string)
+	for _, p := range providers {
+		values, err := p.Attributes(ctx, req)
+		if err != nil {
+			log.Printf("Attribute provider failed: %v", err)
+			continue
+		}
+		for k, v := range values {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"log"
+	"time"
+)
+
+// AuditEvent describes one authorization decision.
+type AuditEvent struct {
+	Time       time.Time
+	Request    Request
+	Decision   Decision
+	Attributes map[string]string
+}
+
+// Auditor records authorization decisions.
+// Record is called synchronously on every check, so it should be fast.
+type Auditor interface {
+	Record(AuditEvent)
+}
+
+// AuditorFunc adapts a function to the Auditor interface.
+type AuditorFunc func(AuditEvent)
+
+func (f AuditorFunc) Record(e AuditEvent) { f(e) }
+
+// LogAuditor writes one line per decision to Logger, or to the standard
+// logger when Logger is nil.
+type LogAuditor struct {
+	Logger *log.Logger
+}
+
+func (l LogAuditor) Record(e AuditEvent) {
+	logf := log.Printf
+	if l.Logger != nil {
+		logf = l.Logger.Printf
+	}
+
+	verdict := "denied"
+	if e.Decision.Allowed {
+		verdict = "granted"
+	}
+	logf("Access %s: user=%s, resource=%s, action=%s, cached=%t",
+		verdict, e.Request.Subject, e.Request.Object, e.Request.Action, e.Decision.Cached)
+}
+
+// NopAuditor discards all events.
+type NopAuditor struct{}
+
+func (NopAuditor) Record(AuditEvent) {}
@@ -0,0 +1,201 @@
+// Package authz is the authorization core of the example server: a Casbin
+// policy engine with a decision cache, audit hook and pluggable attribute
+// providers. It has no HTTP dependencies, so it can be embedded in any Go
+// process for in-process checks with the same policy semantics as the
+// server.
+//
+//	a, err := authz.New(authz.Config{
+//		ModelPath:  "model.conf",
+//		PolicyPath: "policy.csv",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	d, err := a.Authorize(ctx, authz.Request{Subject: "bob", Object: "/api/documents", Action: "GET"})
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// Config configures an Authorizer.
+type Config struct {
+	// ModelPath is the Casbin model file. Required.
+	ModelPath string
+	// PolicyPath is the Casbin CSV policy file. Required.
+	PolicyPath string
+
+	// CacheTTL is how long decisions are cached. Zero disables the cache.
+	CacheTTL time.Duration
+	// CacheSize bounds the number of cached decisions (default 10000).
+	CacheSize int
+
+	// Auditor receives every decision. Defaults to LogAuditor.
+	Auditor Auditor
+	// AttributeProviders enrich requests before they are audited.
+	AttributeProviders []AttributeProvider
+}
+
+// Request is a single authorization question: may Subject perform
+// Action on Object?
+type Request struct {
+	Subject string
+	Object  string
+	Action  string
+}
+
+// Decision is the outcome of an authorization check.
+type Decision struct {
+	Allowed bool
+	// Cached reports whether the decision was served from the cache.
+	Cached bool
+}
+
+// Authorizer answers authorization questions and exposes the policy it
+// evaluates against.
+type Authorizer interface {
+	// Authorize evaluates req against the policy.
+	Authorize(ctx context.Context, req Request) (Decision, error)
+	// RolesForUser returns the roles assigned directly to user.
+	RolesForUser(user string) ([]string, error)
+	// ImplicitRolesForUser returns the roles held by user, including
+	// inherited ones.
+	ImplicitRolesForUser(user string) ([]string, error)
+	// PermissionsForUser returns the permissions held by user, including
+	// those granted through roles.
+	PermissionsForUser(user string) ([][]string, error)
+	// Policies returns all permission rules (p lines).
+	Policies() [][]string
+	// RoleAssignments returns all role assignments (g lines).
+	RoleAssignments() [][]string
+	// Coverage cross-references routes with the policy.
+	Coverage(routes []Route) (CoverageReport, error)
+
+	// AddPolicy grants act on obj to sub. It reports whether the policy
+	// changed.
+	AddPolicy(sub, obj, act string) (bool, error)
+	// RemovePolicy revokes act on obj from sub.
+	RemovePolicy(sub, obj, act string) (bool, error)
+	// AddRoleAssignment assigns role to user.
+	AddRoleAssignment(user, role string) (bool, error)
+	// RemoveRoleAssignment removes role from user.
+	RemoveRoleAssignment(user, role string) (bool, error)
+}
+
+type authorizer struct {
+	store     *snapshotStore
+	cache     *decisionCache
+	auditor   Auditor
+	providers []AttributeProvider
+}
+
+// New creates an Authorizer from cfg. It loads the model and policy but
+// does not start any servers or background goroutines.
+func New(cfg Config) (Authorizer, error) {
+	if cfg.ModelPath == "" || cfg.PolicyPath == "" {
+		return nil, errors.New("authz: ModelPath and PolicyPath are required")
+	}
+
+	store, err := newSnapshotStore(cfg.ModelPath, cfg.PolicyPath)
+	if err != nil {
+		return nil, fmt.Errorf("authz: initialize casbin: %w", err)
+	}
+
+	a := &authorizer{
+		store:     store,
+		auditor:   cfg.Auditor,
+		providers: cfg.AttributeProviders,
+	}
+	if a.auditor == nil {
+		a.auditor = LogAuditor{}
+	}
+	if cfg.CacheTTL > 0 {
+		a.cache = newDecisionCache(cfg.CacheTTL, cfg.CacheSize)
+	}
+
+	return a, nil
+}
+
+func (a *authorizer) Authorize(ctx context.Context, req Request) (Decision, error) {
+	var d Decision
+
+	enforcer, version := a.store.acquire()
+	if allowed, ok := a.cache.get(req, version); ok {
+		d = Decision{Allowed: allowed, Cached: true}
+	} else {
+		allowed, err := enforcer.Enforce(req.Subject, req.Object, req.Action)
+		if err != nil {
+			return Decision{}, fmt.Errorf("authz: enforce: %w", err)
+		}
+		a.cache.put(req, version, allowed)
+		d = Decision{Allowed: allowed}
+	}
+
+	a.auditor.Record(AuditEvent{
+		Time:       time.Now(),
+		Request:    req,
+		Decision:   d,
+		Attributes: collectAttributes(ctx, a.providers, req),
+	})
+
+	return d, nil
+}
+
+func (a *authorizer) RolesForUser(user string) ([]string, error) {
+	return a.store.reader().GetRolesForUser(user)
+}
+
+func (a *authorizer) ImplicitRolesForUser(user string) ([]string, error) {
+	return a.store.reader().GetImplicitRolesForUser(user)
+}
+
+func (a *authorizer) PermissionsForUser(user string) ([][]string, error) {
+	return a.store.reader().GetImplicitPermissionsForUser(user)
+}
+
+func (a *authorizer) Policies() [][]string {
+	return a.store.reader().GetPolicy()
+}
+
+func (a *authorizer) RoleAssignments() [][]string {
+	return a.store.reader().GetGroupingPolicy()
+}
+
+func (a *authorizer) AddPolicy(sub, obj, act string) (bool, error) {
+	return a.update(func(e *casbin.Enforcer) (bool, error) {
+		return e.AddPolicy(sub, obj, act)
+	})
+}
+
+func (a *authorizer) RemovePolicy(sub, obj, act string) (bool, error) {
+	return a.update(func(e *casbin.Enforcer) (bool, error) {
+		return e.RemovePolicy(sub, obj, act)
+	})
+}
+
+func (a *authorizer) AddRoleAssignment(user, role string) (bool, error) {
+	return a.update(func(e *casbin.Enforcer) (bool, error) {
+		return e.AddGroupingPolicy(user, role)
+	})
+}
+
+func (a *authorizer) RemoveRoleAssignment(user, role string) (bool, error) {
+	return a.update(func(e *casbin.Enforcer) (bool, error) {
+		return e.RemoveGroupingPolicy(user, role)
+	})
+}
+
+// update applies a policy change. Cached decisions need no explicit
+// invalidation: the change publishes a new snapshot version.
+func (a *authorizer) update(fn func(e *casbin.Enforcer) (bool, error)) (bool, error) {
+	changed, err := a.store.update(fn)
+	if err != nil {
+		return changed, fmt.Errorf("authz: update policy: %w", err)
+	}
+	return changed, nil
+}
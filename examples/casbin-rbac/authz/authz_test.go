@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeTestPolicy writes policy to a temporary file so tests never touch
+// policy.csv, and returns its path.
+func writeTestPolicy(t testing.TB, policy string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(path, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestAuthorizer builds an Authorizer over the example model and the
+// given policy.
+func newTestAuthorizer(t *testing.T, policy string, cfg Config) Authorizer {
+	t.Helper()
+
+	cfg.ModelPath = "../model.conf"
+	cfg.PolicyPath = writeTestPolicy(t, policy)
+	if cfg.Auditor == nil {
+		cfg.Auditor = NopAuditor{}
+	}
+
+	a, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestNewRequiresPaths(t *testing.T) {
+	policy := writeTestPolicy(t, "p, admin, /api/*, *\n")
+
+	for _, cfg := range []Config{
+		{PolicyPath: policy},
+		{ModelPath: "../model.conf"},
+		{},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
+
+// recordingAuditor collects audit events.
+type recordingAuditor struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (r *recordingAuditor) Record(e AuditEvent) {
+	r.mu.Lock()
+	r.events = append(r.events, e)
+	r.mu.Unlock()
+}
+
+func TestAuthorizeAuditsEveryDecision(t *testing.T) {
+	auditor := &recordingAuditor{}
+	a := newTestAuthorizer(t, "p, user, /api/docs, GET\ng, bob, user\n", Config{
+		CacheTTL: time.Hour,
+		Auditor:  auditor,
+	})
+
+	req := Request{Subject: "bob", Object: "/api/docs", Action: "GET"}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authorize(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	denied := Request{Subject: "bob", Object: "/api/docs", Action: "DELETE"}
+	if _, err := a.Authorize(context.Background(), denied); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		req      Request
+		decision Decision
+	}{
+		{req, Decision{Allowed: true}},
+		{req, Decision{Allowed: true, Cached: true}},
+		{denied, Decision{Allowed: false}},
+	}
+	if len(auditor.events) != len(want) {
+		t.Fatalf("got %d audit events, want %d", len(auditor.events), len(want))
+	}
+	for i, w := range want {
+		e := auditor.events[i]
+		if e.Request != w.req || e.Decision != w.decision {
+			t.Errorf("event %d = %+v %+v, want %+v %+v", i, e.Request, e.Decision, w.req, w.decision)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+}
+
+func TestAttributeProviders(t *testing.T) {
+	auditor := &recordingAuditor{}
+	static := func(attrs map[string]string) AttributeProvider {
+		return AttributeProviderFunc(func(context.Context, Request) (map[string]string, error) {
+			return attrs, nil
+		})
+	}
+	failing := AttributeProviderFunc(func(context.Context, Request) (map[string]string, error) {
+		return map[string]string{"ignored": "x"}, errors.New("provider down")
+	})
+
+	a := newTestAuthorizer(t, "p, user, /api/docs, GET\ng, bob, user\n", Config{
+		Auditor: auditor,
+		AttributeProviders: []AttributeProvider{
+			static(map[string]string{"tenant": "acme", "region": "eu"}),
+			failing,
+			static(map[string]string{"region": "us"}),
+		},
+	})
+
+	d, err := a.Authorize(context.Background(), Request{Subject: "bob", Object: "/api/docs", Action: "GET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Allowed {
+		t.Error("a failing provider blocked the decision")
+	}
+
+	want := map[string]string{"tenant": "acme", "region": "us"}
+	if got := auditor.events[0].Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+}
+
+func TestNoAttributeProviders(t *testing.T) {
+	if got := collectAttributes(context.Background(), nil, Request{}); got != nil {
+		t.Errorf("attributes = %v, want nil", got)
+	}
+}
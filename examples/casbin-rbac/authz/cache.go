@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultCacheSize = 10000
+
+type cacheEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+// decisionCache is a TTL cache of enforcement results for one policy
+// version. Storing a result for a newer version drops everything cached
+// for older ones, and lookups for any other version miss, so a decision
+// made under an old policy is never served. A nil cache is valid and
+// never hits.
+type decisionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	size    int
+	version uint64
+	entries map[Request]cacheEntry
+}
+
+func newDecisionCache(ttl time.Duration, size int) *decisionCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+	return &decisionCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[Request]cacheEntry),
+	}
+}
+
+func (c *decisionCache) get(req Request, version uint64) (allowed, ok bool) {
+	if c == nil {
+		return false, false
+	}
+
+	c.mu.RLock()
+	entry, found := c.entries[req]
+	current := c.version
+	c.mu.RUnlock()
+
+	if !found || version != current || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.allowed, true
+}
+
+func (c *decisionCache) put(req Request, version uint64, allowed bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case version < c.version:
+		// Decided under a policy that has since changed
+		return
+	case version > c.version:
+		c.version = version
+		c.entries = make(map[Request]cacheEntry)
+	case len(c.entries) >= c.size:
+		// Drop everything rather than track recency; the cache refills quickly
+		c.entries = make(map[Request]cacheEntry)
+	}
+	c.entries[req] = cacheEntry{allowed: allowed, expires: time.Now().Add(c.ttl)}
+}
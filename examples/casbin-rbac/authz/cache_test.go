@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecisionCacheVersions(t *testing.T) {
+	c := newDecisionCache(time.Hour, 0)
+	docs := Request{Subject: "bob", Object: "/api/docs", Action: "GET"}
+	users := Request{Subject: "bob", Object: "/api/users", Action: "GET"}
+
+	c.put(docs, 1, true)
+	if allowed, ok := c.get(docs, 1); !ok || !allowed {
+		t.Fatalf("get(v1) = %t, %t; want true, true", allowed, ok)
+	}
+	if _, ok := c.get(docs, 2); ok {
+		t.Error("entry for v1 served to a v2 lookup")
+	}
+
+	// A result decided under an older policy must not be stored
+	c.put(users, 0, true)
+	if _, ok := c.get(users, 0); ok {
+		t.Error("stale v0 result was stored after v1")
+	}
+
+	// Storing a newer version drops older entries
+	c.put(users, 2, false)
+	if _, ok := c.get(docs, 1); ok {
+		t.Error("v1 entry survived a v2 put")
+	}
+	if allowed, ok := c.get(users, 2); !ok || allowed {
+		t.Errorf("get(v2) = %t, %t; want false, true", allowed, ok)
+	}
+}
+
+func TestDecisionCacheExpiryAndSize(t *testing.T) {
+	c := newDecisionCache(time.Nanosecond, 0)
+	req := Request{Subject: "bob", Object: "/api/docs", Action: "GET"}
+	c.put(req, 0, true)
+	time.Sleep(time.Millisecond)
+	if _, ok := c.get(req, 0); ok {
+		t.Error("expired entry served")
+	}
+
+	c = newDecisionCache(time.Hour, 2)
+	for _, obj := range []string{"/a", "/b", "/c"} {
+		c.put(Request{Subject: "bob", Object: obj, Action: "GET"}, 0, true)
+	}
+	if n := len(c.entries); n > 2 {
+		t.Errorf("cache holds %d entries, limit is 2", n)
+	}
+}
+
+func TestNilDecisionCache(t *testing.T) {
+	var c *decisionCache
+	req := Request{Subject: "bob", Object: "/api/docs", Action: "GET"}
+	c.put(req, 0, true)
+	if _, ok := c.get(req, 0); ok {
+		t.Error("nil cache returned a hit")
+	}
+}
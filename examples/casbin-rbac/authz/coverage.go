@@ -0,0 +1,119 @@
+package authz
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// Route is an endpoint exposed by the host application.
+type Route struct {
+	// Method is the HTTP method, or "*" for routes that accept any method.
+	// "*" is only allowed for roles whose policy grants action "*".
+	Method string `json:"method"`
+	// Path is a Casbin keyMatch2 pattern, e.g. "/api/documents/:id".
+	Path string `json:"path"`
+	// Protected reports whether requests to the route go through
+	// authorization at all.
+	Protected bool `json:"protected"`
+	// Public marks unprotected routes that are intentionally open.
+	Public bool `json:"public"`
+}
+
+// RouteCoverage lists the roles allowed to reach a route.
+type RouteCoverage struct {
+	Route
+	Roles []string `json:"roles"`
+}
+
+// CoverageReport cross-references routes with the policy.
+type CoverageReport struct {
+	Routes []RouteCoverage `json:"routes"`
+	// Unreachable are protected routes that no role may call.
+	Unreachable []Route `json:"unreachable"`
+	// Open are routes served without any authorization check that
+	// were not declared public.
+	Open []Route `json:"open"`
+	// UnusedRoles are roles whose permissions match no registered route.
+	UnusedRoles []string `json:"unused_roles"`
+}
+
+// HasFindings reports whether the report flags any misconfiguration.
+func (r CoverageReport) HasFindings() bool {
+	return len(r.Unreachable) > 0 || len(r.Open) > 0 || len(r.UnusedRoles) > 0
+}
+
+// Coverage evaluates every protected route against every role in the
+// policy. Checks bypass the decision cache and auditor.
+func (a *authorizer) Coverage(routes []Route) (CoverageReport, error) {
+	// Use one snapshot throughout so the report reflects a single policy version
+	enforcer := a.store.reader()
+	roles := policyRoles(enforcer)
+	report := CoverageReport{
+		Routes:      make([]RouteCoverage, 0, len(routes)),
+		Unreachable: []Route{},
+		Open:        []Route{},
+		UnusedRoles: []string{},
+	}
+	used := make(map[string]bool, len(roles))
+
+	for _, route := range routes {
+		rc := RouteCoverage{Route: route, Roles: []string{}}
+
+		if !route.Protected {
+			if !route.Public {
+				report.Open = append(report.Open, route)
+			}
+			report.Routes = append(report.Routes, rc)
+			continue
+		}
+
+		for _, role := range roles {
+			allowed, err := enforcer.Enforce(role, route.Path, route.Method)
+			if err != nil {
+				return CoverageReport{}, fmt.Errorf("authz: enforce %s %s as %s: %w", route.Method, route.Path, role, err)
+			}
+			if allowed {
+				rc.Roles = append(rc.Roles, role)
+				used[role] = true
+			}
+		}
+
+		if len(rc.Roles) == 0 {
+			report.Unreachable = append(report.Unreachable, route)
+		}
+		report.Routes = append(report.Routes, rc)
+	}
+
+	for _, role := range roles {
+		if !used[role] {
+			report.UnusedRoles = append(report.UnusedRoles, role)
+		}
+	}
+
+	return report, nil
+}
+
+// roles returns every policy subject and every role that is assigned
+// to someone, sorted.
+func policyRoles(e *casbin.Enforcer) []string {
+	seen := make(map[string]bool)
+	for _, p := range e.GetPolicy() {
+		if len(p) > 0 {
+			seen[p[0]] = true
+		}
+	}
+	for _, g := range e.GetGroupingPolicy() {
+		if len(g) > 1 {
+			seen[g[1]] = true
+		}
+	}
+
+	roles := make([]string, 0, len(seen))
+	for role := range seen {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
@@ -0,0 +1,72 @@
+package authz
+
+import (
+	"reflect"
+	"testing"
+)
+
+const coveragePolicy = `
+p, admin, /api/*, *
+p, reader, /api/docs, GET
+p, ghost, /api/unused, GET
+g, alice, reader
+`
+
+func TestCoverage(t *testing.T) {
+	a := newTestAuthorizer(t, coveragePolicy, Config{})
+
+	routes := []Route{
+		{Method: "GET", Path: "/api/docs", Protected: true},
+		{Method: "DELETE", Path: "/api/docs/:id", Protected: true},
+		{Method: "POST", Path: "/internal/jobs", Protected: true},
+		{Method: "*", Path: "/debug/dump"},
+		{Method: "GET", Path: "/health", Public: true},
+	}
+
+	report, err := a.Coverage(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRoles := [][]string{
+		{"admin", "reader"},
+		{"admin"},
+		{},
+		{},
+		{},
+	}
+	for i, rc := range report.Routes {
+		if !reflect.DeepEqual(rc.Roles, wantRoles[i]) {
+			t.Errorf("%s %s: roles = %v, want %v", rc.Method, rc.Path, rc.Roles, wantRoles[i])
+		}
+	}
+
+	if want := []Route{routes[2]}; !reflect.DeepEqual(report.Unreachable, want) {
+		t.Errorf("Unreachable = %v, want %v", report.Unreachable, want)
+	}
+	if want := []Route{routes[3]}; !reflect.DeepEqual(report.Open, want) {
+		t.Errorf("Open = %v, want %v", report.Open, want)
+	}
+	if want := []string{"ghost"}; !reflect.DeepEqual(report.UnusedRoles, want) {
+		t.Errorf("UnusedRoles = %v, want %v", report.UnusedRoles, want)
+	}
+	if !report.HasFindings() {
+		t.Error("HasFindings() = false, want true")
+	}
+}
+
+func TestCoverageNoFindings(t *testing.T) {
+	a := newTestAuthorizer(t, coveragePolicy, Config{})
+
+	report, err := a.Coverage([]Route{
+		{Method: "GET", Path: "/api/docs", Protected: true},
+		{Method: "GET", Path: "/api/unused", Protected: true},
+		{Method: "GET", Path: "/health", Public: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HasFindings() {
+		t.Errorf("unexpected findings: %+v", report)
+	}
+}
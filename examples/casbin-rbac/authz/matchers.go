@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+)
+
+// fastKeyMatch2 is the name keyMatch2 is installed under. casbin's
+// AddFunction never replaces an existing function, so the builtin
+// keyMatch2 cannot be overridden; the matcher is rewritten to call this
+// name instead.
+const fastKeyMatch2 = "fastKeyMatch2"
+
+var (
+	keyMatch2Call  = regexp.MustCompile(`\bkeyMatch2\(`)
+	keyMatch2Param = regexp.MustCompile(`:[^/]+`)
+
+	// newKeyMatch2 builds the function installed by freeze. Tests replace
+	// it to observe calls.
+	newKeyMatch2 = precompiledKeyMatch2
+)
+
+// installKeyMatch2 makes e's matcher call fn wherever it called keyMatch2.
+func installKeyMatch2(e *casbin.Enforcer, fn func(args ...interface{}) (interface{}, error)) {
+	m := e.GetModel()["m"]["m"]
+	m.Value = keyMatch2Call.ReplaceAllString(m.Value, fastKeyMatch2+"(")
+	e.AddFunction(fastKeyMatch2, fn)
+}
+
+// compileKeyMatch2 builds the regexp casbin's keyMatch2 matches pattern
+// with: "/*" matches anything and ":name" matches one path segment.
+func compileKeyMatch2(pattern string) (*regexp.Regexp, error) {
+	expr := strings.ReplaceAll(pattern, "/*", "/.*")
+	expr = keyMatch2Param.ReplaceAllString(expr, "[^/]+")
+	return regexp.Compile("^" + expr + "$")
+}
+
+// precompiledKeyMatch2 returns a drop-in keyMatch2 with the regexp of
+// every value in policies compiled up front. casbin's own keyMatch2
+// compiles a regexp on every call, which dominates Enforce time. A
+// snapshot's policy never changes, so the map is read-only and needs no
+// lock; patterns it does not know fall back to casbin.
+func precompiledKeyMatch2(policies [][]string) func(args ...interface{}) (interface{}, error) {
+	compiled := make(map[string]*regexp.Regexp)
+	for _, rule := range policies {
+		for _, value := range rule {
+			if _, ok := compiled[value]; ok {
+				continue
+			}
+			if re, err := compileKeyMatch2(value); err == nil {
+				compiled[value] = re
+			}
+		}
+	}
+
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, errors.New("keyMatch2: expected 2 arguments")
+		}
+		key, ok1 := args[0].(string)
+		pattern, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return false, errors.New("keyMatch2: arguments must be strings")
+		}
+
+		if re, ok := compiled[pattern]; ok {
+			return re.MatchString(key), nil
+		}
+		return util.KeyMatch2(key, pattern), nil
+	}
+}
@@ -0,0 +1,40 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+func TestPrecompiledKeyMatch2MatchesCasbin(t *testing.T) {
+	patterns := []string{"/api/*", "/api/documents", "/api/documents/:id", "/api/:kind/:id/history", "/*"}
+	keys := []string{
+		"/api", "/api/", "/api/documents", "/api/documents/1", "/api/documents/1/2",
+		"/api/users/7/history", "/api/users//history", "/other", "",
+	}
+
+	// Leave one pattern out to exercise the casbin fallback
+	match := precompiledKeyMatch2([][]string{patterns[:len(patterns)-1]})
+
+	for _, pattern := range patterns {
+		for _, key := range keys {
+			got, err := match(key, pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := util.KeyMatch2(key, pattern); got != want {
+				t.Errorf("keyMatch2(%q, %q) = %v, casbin says %v", key, pattern, got, want)
+			}
+		}
+	}
+}
+
+func TestPrecompiledKeyMatch2BadArguments(t *testing.T) {
+	match := precompiledKeyMatch2(nil)
+	if _, err := match("/api"); err == nil {
+		t.Error("expected an error for one argument")
+	}
+	if _, err := match("/api", 1); err == nil {
+		t.Error("expected an error for a non-string argument")
+	}
+}
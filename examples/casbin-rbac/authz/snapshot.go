@@ -0,0 +1,114 @@
+package authz
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// snapshot is an immutable enforcer built from one version of the
+// policy. It is never mutated after publish, so Enforce on it needs no
+// lock.
+type snapshot struct {
+	version  uint64
+	enforcer *casbin.Enforcer
+}
+
+// snapshotStore separates reads from writes. Writes go to the primary
+// enforcer, which owns the adapter; checks run against the current
+// snapshot without ever waiting for a writer. A single snapshot is
+// enough because nothing mutates it: every goroutine can share it, and
+// a write replaces it instead of changing it.
+type snapshotStore struct {
+	mu      sync.Mutex // serializes writes to primary
+	primary *casbin.Enforcer
+
+	current atomic.Pointer[snapshot]
+}
+
+func newSnapshotStore(modelPath, policyPath string) (*snapshotStore, error) {
+	primary, err := casbin.NewEnforcer(modelPath, policyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save each change through the adapter. The file adapter does not
+	// implement incremental saves and casbin ignores that, so with a
+	// policy file changes stay in memory and are lost on restart.
+	primary.EnableAutoSave(true)
+
+	e, err := cloneEnforcer(primary)
+	if err != nil {
+		return nil, err
+	}
+	freeze(e)
+
+	s := &snapshotStore{primary: primary}
+	s.current.Store(&snapshot{enforcer: e})
+	return s, nil
+}
+
+// reader returns the enforcer of the current snapshot.
+// The caller must not mutate it.
+func (s *snapshotStore) reader() *casbin.Enforcer {
+	return s.current.Load().enforcer
+}
+
+// acquire is like reader but also returns the snapshot version.
+func (s *snapshotStore) acquire() (*casbin.Enforcer, uint64) {
+	snap := s.current.Load()
+	return snap.enforcer, snap.version
+}
+
+// update applies fn to a copy of the policy first, then to the primary,
+// and finally publishes the copy. If fn fails on either, the primary
+// and readers stay on the previous version. The primary saves the change
+// through its adapter only if the adapter supports it; see
+// newSnapshotStore.
+func (s *snapshotStore) update(fn func(e *casbin.Enforcer) (bool, error)) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := cloneEnforcer(s.primary)
+	if err != nil {
+		return false, err
+	}
+	changed, err := fn(next)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	// casbin only changes the primary's model once the adapter accepted
+	// (or did not implement) the change, so a failed save leaves the
+	// primary untouched
+	if _, err := fn(s.primary); err != nil {
+		return false, err
+	}
+
+	freeze(next)
+	s.current.Store(&snapshot{version: s.current.Load().version + 1, enforcer: next})
+	return true, nil
+}
+
+// cloneEnforcer builds an adapter-less enforcer from a copy of e's model.
+func cloneEnforcer(e *casbin.Enforcer) (*casbin.Enforcer, error) {
+	clone, err := casbin.NewEnforcer(e.GetModel().Copy())
+	if err != nil {
+		return nil, fmt.Errorf("clone enforcer: %w", err)
+	}
+	// A model-only enforcer has no adapter, so role links are not built
+	// by LoadPolicy
+	if err := clone.BuildRoleLinks(); err != nil {
+		return nil, fmt.Errorf("clone enforcer role links: %w", err)
+	}
+	return clone, nil
+}
+
+// freeze prepares an enforcer for publishing by switching its matcher
+// to keyMatch2 with the policy's patterns precompiled. It must be the
+// last change made to e.
+func freeze(e *casbin.Enforcer) {
+	installKeyMatch2(e, newKeyMatch2(e.GetPolicy()))
+}
@@ -0,0 +1,315 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+const snapshotPolicy = `
+p, admin, /api/*, *
+p, user, /api/docs, GET
+g, bob, user
+g, root, admin
+`
+
+func allowed(t *testing.T, a Authorizer, sub, obj, act string) Decision {
+	t.Helper()
+
+	d, err := a.Authorize(context.Background(), Request{Subject: sub, Object: obj, Action: act})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteVisibleToNextAuthorize(t *testing.T) {
+	a := newTestAuthorizer(t, snapshotPolicy, Config{CacheTTL: time.Hour})
+
+	if d := allowed(t, a, "bob", "/api/docs", "POST"); d.Allowed {
+		t.Fatal("bob may POST before the policy change")
+	}
+
+	if changed, err := a.AddPolicy("user", "/api/docs", "POST"); err != nil || !changed {
+		t.Fatalf("AddPolicy = %t, %v", changed, err)
+	}
+	d := allowed(t, a, "bob", "/api/docs", "POST")
+	if !d.Allowed || d.Cached {
+		t.Errorf("after AddPolicy: %+v, want allowed and not cached", d)
+	}
+
+	if changed, err := a.RemoveRoleAssignment("bob", "user"); err != nil || !changed {
+		t.Fatalf("RemoveRoleAssignment = %t, %v", changed, err)
+	}
+	if d := allowed(t, a, "bob", "/api/docs", "GET"); d.Allowed {
+		t.Error("bob may still GET after losing the user role")
+	}
+
+	if changed, err := a.AddRoleAssignment("bob", "admin"); err != nil || !changed {
+		t.Fatalf("AddRoleAssignment = %t, %v", changed, err)
+	}
+	if d := allowed(t, a, "bob", "/api/users/1", "DELETE"); !d.Allowed {
+		t.Error("bob may not DELETE after becoming admin")
+	}
+}
+
+func TestUnchangedWriteKeepsVersion(t *testing.T) {
+	store, err := newSnapshotStore("../model.conf", writeTestPolicy(t, snapshotPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, before := store.acquire()
+	if changed, err := store.update(func(e *casbin.Enforcer) (bool, error) {
+		return e.AddPolicy("user", "/api/docs", "GET")
+	}); err != nil || changed {
+		t.Fatalf("update = %t, %v; want false, nil for an existing rule", changed, err)
+	}
+	if _, after := store.acquire(); after != before {
+		t.Errorf("version changed from %d to %d without a policy change", before, after)
+	}
+}
+
+func TestFailedWriteLeavesPolicyUnchanged(t *testing.T) {
+	store, err := newSnapshotStore("../model.conf", writeTestPolicy(t, snapshotPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, version := store.acquire()
+	boom := errors.New("boom")
+	changed, err := store.update(func(e *casbin.Enforcer) (bool, error) {
+		e.AddPolicy("user", "/api/docs", "DELETE")
+		return false, boom
+	})
+	if !errors.Is(err, boom) || changed {
+		t.Fatalf("update = %t, %v; want false, boom", changed, err)
+	}
+
+	if r, v := store.acquire(); r != reader || v != version {
+		t.Error("a failed update published a new snapshot")
+	}
+	if ok, _ := store.primary.Enforce("bob", "/api/docs", "DELETE"); ok {
+		t.Error("a failed update changed the primary")
+	}
+	if ok, _ := store.reader().Enforce("bob", "/api/docs", "DELETE"); ok {
+		t.Error("a failed update changed what readers see")
+	}
+}
+
+// Run with -race.
+func TestConcurrentAuthorizeDuringWrites(t *testing.T) {
+	a := newTestAuthorizer(t, snapshotPolicy, Config{CacheTTL: time.Hour})
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				d, err := a.Authorize(context.Background(), Request{Subject: "root", Object: "/api/docs", Action: "GET"})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !d.Allowed {
+					t.Error("root denied while unrelated rules changed")
+					return
+				}
+				// This rule toggles; either answer is fine, it must not race
+				if _, err := a.Authorize(context.Background(), Request{Subject: "bob", Object: "/api/reports", Action: "GET"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		var err error
+		if i%2 == 0 {
+			_, err = a.AddPolicy("user", "/api/reports", "GET")
+		} else {
+			_, err = a.RemovePolicy("user", "/api/reports", "GET")
+		}
+		if err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if d := allowed(t, a, "bob", "/api/reports", "GET"); d.Allowed {
+		t.Error("bob may GET /api/reports after the last write removed it")
+	}
+}
+
+func TestPublishedSnapshotUsesPrecompiledKeyMatch2(t *testing.T) {
+	var calls atomic.Int64
+	orig := newKeyMatch2
+	t.Cleanup(func() { newKeyMatch2 = orig })
+	newKeyMatch2 = func(policies [][]string) func(...interface{}) (interface{}, error) {
+		match := precompiledKeyMatch2(policies)
+		return func(args ...interface{}) (interface{}, error) {
+			calls.Add(1)
+			return match(args...)
+		}
+	}
+
+	a := newTestAuthorizer(t, snapshotPolicy, Config{})
+	if d := allowed(t, a, "bob", "/api/docs", "GET"); !d.Allowed {
+		t.Fatal("bob may not GET /api/docs")
+	}
+	if calls.Load() == 0 {
+		t.Fatal("the initial snapshot did not call the installed keyMatch2")
+	}
+
+	if _, err := a.AddPolicy("user", "/api/reports/:id", "GET"); err != nil {
+		t.Fatal(err)
+	}
+	calls.Store(0)
+	if d := allowed(t, a, "bob", "/api/reports/7", "GET"); !d.Allowed {
+		t.Fatal("bob may not GET /api/reports/7 after AddPolicy")
+	}
+	if calls.Load() == 0 {
+		t.Error("the snapshot published by a write did not call the installed keyMatch2")
+	}
+}
+
+// Compare a single SyncedEnforcer, whose RWMutex makes checks wait for
+// writers, with the snapshot store, for read-only load and for load where
+// one in every writeEvery operations changes the policy. "synced" uses
+// casbin's builtin keyMatch2; "synced-precompiled" uses the same
+// precompiled keyMatch2 as the snapshots, so it isolates the cost of
+// locking.
+//
+//	go test -run '^$' -bench=Enforce -cpu=1,4,16 ./authz
+
+var benchRequests = [][]interface{}{
+	{"alice", "/api/documents/1", "PUT"},
+	{"bob", "/api/documents", "GET"},
+	{"bob", "/api/documents/1", "DELETE"},
+	{"charlie", "/api/users", "GET"},
+	{"admin_user", "/api/users/7", "DELETE"},
+}
+
+type benchEngine struct {
+	enforce func(rvals ...interface{}) (bool, error)
+	write   func(add bool) error
+}
+
+func newSyncedBenchEngine(precompiled bool) func(b *testing.B) benchEngine {
+	return func(b *testing.B) benchEngine {
+		e, err := casbin.NewSyncedEnforcer("../model.conf", writeTestPolicy(b, benchPolicy(b)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if precompiled {
+			installKeyMatch2(e.Enforcer, precompiledKeyMatch2(e.GetPolicy()))
+		}
+
+		return benchEngine{
+			enforce: e.Enforce,
+			write: func(add bool) error {
+				if add {
+					_, err := e.AddPolicy("user", "/api/reports", "GET")
+					return err
+				}
+				_, err := e.RemovePolicy("user", "/api/reports", "GET")
+				return err
+			},
+		}
+	}
+}
+
+func newSnapshotBenchEngine(b *testing.B) benchEngine {
+	store, err := newSnapshotStore("../model.conf", writeTestPolicy(b, benchPolicy(b)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return benchEngine{
+		enforce: func(rvals ...interface{}) (bool, error) {
+			return store.reader().Enforce(rvals...)
+		},
+		write: func(add bool) error {
+			_, err := store.update(func(e *casbin.Enforcer) (bool, error) {
+				if add {
+					return e.AddPolicy("user", "/api/reports", "GET")
+				}
+				return e.RemovePolicy("user", "/api/reports", "GET")
+			})
+			return err
+		},
+	}
+}
+
+// benchPolicy returns the example policy.csv.
+func benchPolicy(b *testing.B) string {
+	data, err := os.ReadFile("../policy.csv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(data)
+}
+
+func BenchmarkEnforce(b *testing.B) {
+	engines := []struct {
+		name string
+		new  func(*testing.B) benchEngine
+	}{
+		{"synced", newSyncedBenchEngine(false)},
+		{"synced-precompiled", newSyncedBenchEngine(true)},
+		{"snapshot", newSnapshotBenchEngine},
+	}
+
+	for _, writeEvery := range []int{0, 1000, 100} {
+		load := "reads"
+		if writeEvery > 0 {
+			load = fmt.Sprintf("writes-1-in-%d", writeEvery)
+		}
+		for _, engine := range engines {
+			b.Run(engine.name+"/"+load, func(b *testing.B) {
+				benchmarkEngine(b, engine.new(b), writeEvery)
+			})
+		}
+	}
+}
+
+func benchmarkEngine(b *testing.B, engine benchEngine, writeEvery int) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if writeEvery > 0 && i%writeEvery == 0 {
+				if err := engine.write(i/writeEvery%2 == 1); err != nil {
+					b.Error(err)
+					return
+				}
+				continue
+			}
+			if _, err := engine.enforce(benchRequests[i%len(benchRequests)]...); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
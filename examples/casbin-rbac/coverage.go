@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"casbin-rbac-example/authz"
+)
+
+// routeVar matches mux path variables such as {id} or {id:[0-9]+}.
+var routeVar = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// routes lists every registered route that serves requests, converted to
+// the keyMatch2 syntax used in policy.csv ({id} becomes :id). Routes
+// without a method restriction are listed once with method "*", and routes
+// without a path template (they match any path) with path "*".
+func (s *Server) routes() ([]authz.Route, error) {
+	var routes []authz.Route
+
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			// Prefix routes that only mount a subrouter serve nothing themselves
+			return nil
+		}
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			tmpl = "*"
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"*"}
+		}
+
+		protected := s.protected[route]
+		for _, ancestor := range ancestors {
+			protected = protected || s.protected[ancestor]
+		}
+
+		path := routeVar.ReplaceAllString(tmpl, ":$1")
+		for _, method := range methods {
+			routes = append(routes, authz.Route{
+				Method:    method,
+				Path:      path,
+				Protected: protected,
+				Public:    s.public[route],
+			})
+		}
+		return nil
+	})
+
+	return routes, err
+}
+
+func (s *Server) coverageReport() (authz.CoverageReport, error) {
+	routes, err := s.routes()
+	if err != nil {
+		return authz.CoverageReport{}, err
+	}
+	return s.authz.Coverage(routes)
+}
+
+func (s *Server) coverageReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := s.coverageReport()
+	if err != nil {
+		log.Printf("Coverage report failed: %v", err)
+		sendError(w, r, http.StatusInternalServerError, msgReportFailed)
+		return
+	}
+
+	sendSuccess(w, report)
+}
+
+// runCoverageReport prints the coverage report and returns the process
+// exit code: 1 if the report has findings, 2 if it could not be built.
+func runCoverageReport(s *Server) int {
+	report, err := s.coverageReport()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "coverage report failed: %v\n", err)
+		return 2
+	}
+
+	writeCoverageReport(os.Stdout, report)
+	if report.HasFindings() {
+		return 1
+	}
+	return 0
+}
+
+func writeCoverageReport(w io.Writer, report authz.CoverageReport) {
+	fmt.Fprintln(w, "Route coverage:")
+	for _, rc := range report.Routes {
+		var access string
+		switch {
+		case !rc.Protected && rc.Public:
+			access = "public"
+		case !rc.Protected:
+			access = "OPEN (no authorization)"
+		case len(rc.Roles) == 0:
+			access = "UNREACHABLE (no role)"
+		default:
+			access = strings.Join(rc.Roles, ", ")
+		}
+		fmt.Fprintf(w, "  %-7s %-30s %s\n", rc.Method, rc.Path, access)
+	}
+
+	fmt.Fprintln(w)
+	if !report.HasFindings() {
+		fmt.Fprintln(w, "No findings.")
+		return
+	}
+	for _, route := range report.Unreachable {
+		fmt.Fprintf(w, "Unreachable route: %s %s is allowed for no role\n", route.Method, route.Path)
+	}
+	for _, route := range report.Open {
+		fmt.Fprintf(w, "Open route: %s %s is served without authorization\n", route.Method, route.Path)
+	}
+	for _, role := range report.UnusedRoles {
+		fmt.Fprintf(w, "Unused role: %s has no permission matching a registered route\n", role)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"casbin-rbac-example/authz"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	authorizer, err := authz.New(authz.Config{
+		ModelPath:  "model.conf",
+		PolicyPath: "policy.csv",
+		Auditor:    authz.NopAuditor{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newServer(authorizer)
+}
+
+func TestCoverageReportDefaultPolicy(t *testing.T) {
+	s := newTestServer(t)
+
+	report, err := s.coverageReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HasFindings() {
+		t.Errorf("unexpected findings: open=%v unreachable=%v unused=%v",
+			report.Open, report.Unreachable, report.UnusedRoles)
+	}
+}
+
+func TestCoverageReportMethodlessAndNestedRoutes(t *testing.T) {
+	s := newTestServer(t)
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	// Unprotected route without a method restriction
+	s.router.HandleFunc("/debug/dump", noop)
+
+	// Route on a subrouter nested inside a protected one
+	internal := s.protectedSubrouter("/internal")
+	internal.PathPrefix("/v2").Subrouter().HandleFunc("/secret", noop)
+
+	report, err := s.coverageReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := authz.Route{Method: "*", Path: "/debug/dump"}
+	if len(report.Open) != 1 || report.Open[0] != open {
+		t.Errorf("Open = %v, want [%v]", report.Open, open)
+	}
+
+	secret := authz.Route{Method: "*", Path: "/internal/v2/secret", Protected: true}
+	if len(report.Unreachable) != 1 || report.Unreachable[0] != secret {
+		t.Errorf("Unreachable = %v, want [%v]", report.Unreachable, secret)
+	}
+}
+
+func TestCoverageReportPathlessRoute(t *testing.T) {
+	s := newTestServer(t)
+	s.router.NewRoute().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	report, err := s.coverageReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := authz.Route{Method: "*", Path: "*"}
+	if len(report.Open) != 1 || report.Open[0] != open {
+		t.Errorf("Open = %v, want [%v]", report.Open, open)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Message keys for user-facing error and denial messages.
+// The key is also returned in the response "code" field so clients
+// can branch on it without parsing the translated text.
+const (
+	msgMissingUser      = "missing_user"
+	msgAuthzFailed      = "authorization_failed"
+	msgForbidden        = "insufficient_permissions"
+	msgInvalidBody      = "invalid_request_body"
+	msgDocumentNotFound = "document_not_found"
+	msgReportFailed     = "report_failed"
+)
+
+// defaultLocale is used when the client sends no Accept-Language header
+// or none of the requested languages has a catalog.
+const defaultLocale = "en"
+
+// catalogs holds the translated messages for every supported locale.
+// Every locale must define every key; missing keys fall back to defaultLocale.
+var catalogs = map[string]map[string]string{
+	"en": {
+		msgMissingUser:      "Missing X-User header",
+		msgAuthzFailed:      "Authorization check failed",
+		msgForbidden:        "Insufficient permissions",
+		msgInvalidBody:      "Invalid request body",
+		msgDocumentNotFound: "Document not found",
+		msgReportFailed:     "Report generation failed",
+	},
+	"es": {
+		msgMissingUser:      "Falta la cabecera X-User",
+		msgAuthzFailed:      "Error al comprobar la autorización",
+		msgForbidden:        "Permisos insuficientes",
+		msgInvalidBody:      "Cuerpo de la solicitud no válido",
+		msgDocumentNotFound: "Documento no encontrado",
+		msgReportFailed:     "Error al generar el informe",
+	},
+	"fr": {
+		msgMissingUser:      "En-tête X-User manquant",
+		msgAuthzFailed:      "Échec de la vérification des autorisations",
+		msgForbidden:        "Autorisations insuffisantes",
+		msgInvalidBody:      "Corps de requête invalide",
+		msgDocumentNotFound: "Document introuvable",
+		msgReportFailed:     "Échec de la génération du rapport",
+	},
+	"de": {
+		msgMissingUser:      "X-User-Header fehlt",
+		msgAuthzFailed:      "Berechtigungsprüfung fehlgeschlagen",
+		msgForbidden:        "Unzureichende Berechtigungen",
+		msgInvalidBody:      "Ungültiger Anfragetext",
+		msgDocumentNotFound: "Dokument nicht gefunden",
+		msgReportFailed:     "Berichterstellung fehlgeschlagen",
+	},
+	"ja": {
+		msgMissingUser:      "X-User ヘッダーがありません",
+		msgAuthzFailed:      "認可チェックに失敗しました",
+		msgForbidden:        "権限が不足しています",
+		msgInvalidBody:      "リクエスト本文が無効です",
+		msgDocumentNotFound: "ドキュメントが見つかりません",
+		msgReportFailed:     "レポートの生成に失敗しました",
+	},
+}
+
+// negotiateLocale picks the best supported locale from an Accept-Language
+// header, honouring q-values. Region subtags ("fr-CA") match their base
+// language ("fr") when no exact catalog exists.
+func negotiateLocale(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: tag, q: q})
+	}
+
+	// Stable sort keeps header order for equal weights
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return defaultLocale
+		}
+		if _, ok := catalogs[c.tag]; ok {
+			return c.tag
+		}
+		if base, _, found := strings.Cut(c.tag, "-"); found {
+			if _, ok := catalogs[base]; ok {
+				return base
+			}
+		}
+	}
+
+	return defaultLocale
+}
+
+// translate returns the message for key in locale, falling back to the
+// default locale and finally to the key itself.
+func translate(locale, key string) string {
+	if msg, ok := catalogs[locale][key]; ok {
+		return msg
+	}
+	if msg, ok := catalogs[defaultLocale][key]; ok {
+		return msg
+	}
+	return key
+}
+
+// localize resolves a message key using the request's Accept-Language header.
+func localize(r *http.Request, key string) (locale, message string) {
+	locale = negotiateLocale(r.Header.Get("Accept-Language"))
+	return locale, translate(locale, key)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"DE", "de"},
+		{"fr-CA", "fr"},
+		{"pt-BR", "en"},
+		{"xx, ja", "ja"},
+		{"es;q=0.5, de;q=0.8", "de"},
+		{"es;q=0.8, de;q=0.8", "es"},
+		{"ja;q=0, fr;q=0.1", "fr"},
+		{"ja;q=0", "en"},
+		{"*", "en"},
+		{"xx, *;q=0.5, fr;q=0.4", "en"},
+		{"fr;q=bogus", "fr"},
+		{" , ;q=1", "en"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateLocale(tt.header); got != tt.want {
+			t.Errorf("negotiateLocale(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for key := range catalogs[defaultLocale] {
+		for locale, catalog := range catalogs {
+			if catalog[key] == "" {
+				t.Errorf("locale %q has no message for %q", locale, key)
+			}
+		}
+	}
+	for locale, catalog := range catalogs {
+		for key := range catalog {
+			if _, ok := catalogs[defaultLocale][key]; !ok {
+				t.Errorf("locale %q defines %q, which %q lacks", locale, key, defaultLocale)
+			}
+		}
+	}
+}
+
+func TestTranslateFallback(t *testing.T) {
+	if got := translate("xx", msgForbidden); got != "Insufficient permissions" {
+		t.Errorf("unknown locale: got %q", got)
+	}
+	if got := translate("fr", "no_such_key"); got != "no_such_key" {
+		t.Errorf("unknown key: got %q", got)
+	}
+}
+
+func TestLocalizedForbidden(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest("DELETE", "/api/documents/1", nil)
+	req.Header.Set("X-User", "bob")
+	req.Header.Set("Accept-Language", "fr-CA,en;q=0.5")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", w.Code)
+	}
+	if got := w.Header().Get("Content-Language"); got != "fr" {
+		t.Errorf("Content-Language = %q, want fr", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Language" {
+		t.Errorf("Vary = %q, want Accept-Language", got)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != msgForbidden || resp.Error != "Autorisations insuffisantes" || resp.Success {
+		t.Errorf("response = %+v", resp)
+	}
+}
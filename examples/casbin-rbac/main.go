@@ -5,19 +5,31 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"os"
 	"sync"
 
-	"github.com/casbin/casbin/v2"
 	"github.com/gorilla/mux"
+
+	"casbin-rbac-example/authz"
 )
 
 type Server struct {
-	enforcer *casbin.Enforcer
-	router   *mux.Router
-	mu       sync.RWMutex
-	documents map[int]Document
-	nextID   int
+	authz          authz.Authorizer
+	router         *mux.Router
+	protected      map[*mux.Route]bool
+	public         map[*mux.Route]bool
+	users          map[string]User
+	sessions       *sessionTracker
+	accessRequests *accessRequestStore
+	mu             sync.RWMutex
+	documents      map[int]Document
+	nextID         int
+}
+
+type User struct {
+	Username string `json:"username"`
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email,omitempty"`
 }
 
 type Document struct {
@@ -31,33 +43,28 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
 }
 
 func main() {
-	// Initialize Casbin enforcer
-	enforcer, err := casbin.NewEnforcer("model.conf", "policy.csv")
+	// Initialize authorization core
+	authorizer, err := authz.New(authz.Config{
+		ModelPath:  "model.conf",
+		PolicyPath: "policy.csv",
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize Casbin: %v", err)
 	}
 
-	// Enable auto-save to persist policy changes
-	enforcer.EnableAutoSave(true)
-
 	log.Println("Casbin enforcer initialized successfully")
 
 	// Create server
-	server := &Server{
-		enforcer:  enforcer,
-		router:    mux.NewRouter(),
-		documents: make(map[int]Document),
-		nextID:    1,
-	}
+	server := newServer(authorizer)
 
-	// Add some sample documents
-	server.addSampleData()
-
-	// Setup routes
-	server.setupRoutes()
+	// "coverage" checks the policy against the routes and exits
+	if len(os.Args) > 1 && os.Args[1] == "coverage" {
+		os.Exit(runCoverageReport(server))
+	}
 
 	// Start server
 	addr := ":8080"
@@ -68,14 +75,38 @@ func main() {
 	}
 }
 
+func newServer(authorizer authz.Authorizer) *Server {
+	server := &Server{
+		authz:          authorizer,
+		router:         mux.NewRouter(),
+		protected:      make(map[*mux.Route]bool),
+		public:         make(map[*mux.Route]bool),
+		users:          make(map[string]User),
+		sessions:       newSessionTracker(),
+		documents:      make(map[int]Document),
+		nextID:         1,
+		accessRequests: newAccessRequestStore(),
+	}
+
+	// Add some sample users and documents
+	server.addSampleData()
+
+	// Setup routes
+	server.setupRoutes()
+
+	return server
+}
+
 func (s *Server) setupRoutes() {
-	// Public routes
-	s.router.HandleFunc("/health", s.healthHandler).Methods("GET")
-	s.router.HandleFunc("/", s.homeHandler).Methods("GET")
+	// Public routes (anything outside a protected subrouter not marked
+	// public is reported as open by the coverage report)
+	s.markPublic(
+		s.router.HandleFunc("/health", s.healthHandler).Methods("GET"),
+		s.router.HandleFunc("/", s.homeHandler).Methods("GET"),
+	)
 
 	// API routes with authorization
-	api := s.router.PathPrefix("/api").Subrouter()
-	api.Use(s.authorizationMiddleware)
+	api := s.protectedSubrouter("/api")
 
 	// Document endpoints
 	api.HandleFunc("/documents", s.listDocumentsHandler).Methods("GET")
@@ -91,7 +122,33 @@ func (s *Server) setupRoutes() {
 
 	// Permission endpoints
 	api.HandleFunc("/permissions/{user}", s.getUserPermissionsHandler).Methods("GET")
-	s.router.HandleFunc("/api/policies", s.listPoliciesHandler).Methods("GET")
+	s.markPublic(s.router.HandleFunc("/api/policies", s.listPoliciesHandler).Methods("GET"))
+
+	// Self-service endpoints
+	api.HandleFunc("/me", s.getMeHandler).Methods("GET")
+
+	// Report endpoints
+	api.HandleFunc("/reports/coverage", s.coverageReportHandler).Methods("GET")
+}
+
+// protectedSubrouter mounts a subrouter at prefix behind the
+// authorization middleware. Routes registered on it, or on subrouters
+// nested in it, are reported as protected by the coverage report.
+func (s *Server) protectedSubrouter(prefix string) *mux.Router {
+	mount := s.router.PathPrefix(prefix)
+	s.protected[mount] = true
+
+	sub := mount.Subrouter()
+	sub.Use(s.authorizationMiddleware)
+	return sub
+}
+
+// markPublic records routes that are intentionally served without
+// authorization.
+func (s *Server) markPublic(routes ...*mux.Route) {
+	for _, route := range routes {
+		s.public[route] = true
+	}
 }
 
 func (s *Server) authorizationMiddleware(next http.Handler) http.Handler {
@@ -99,7 +156,7 @@ func (s *Server) authorizationMiddleware(next http.Handler) http.Handler {
 		// Get user from header (in production, use JWT or session)
 		user := r.Header.Get("X-User")
 		if user == "" {
-			sendError(w, http.StatusUnauthorized, "Missing X-User header")
+			sendError(w, r, http.StatusUnauthorized, msgMissingUser)
 			return
 		}
 
@@ -107,28 +164,32 @@ func (s *Server) authorizationMiddleware(next http.Handler) http.Handler {
 		resource := r.URL.Path
 		action := r.Method
 
-		// Check permission
-		allowed, err := s.enforcer.Enforce(user, resource, action)
+		// Check permission (decisions are audited by the authorizer)
+		decision, err := s.authz.Authorize(r.Context(), authz.Request{
+			Subject: user,
+			Object:  resource,
+			Action:  action,
+		})
 		if err != nil {
 			log.Printf("Authorization check failed: %v", err)
-			sendError(w, http.StatusInternalServerError, "Authorization check failed")
+			sendError(w, r, http.StatusInternalServerError, msgAuthzFailed)
 			return
 		}
 
-		if !allowed {
-			log.Printf("Access denied: user=%s, resource=%s, action=%s", user, resource, action)
-			sendError(w, http.StatusForbidden, "Insufficient permissions")
+		if !decision.Allowed {
+			sendError(w, r, http.StatusForbidden, msgForbidden)
 			return
 		}
 
-		log.Printf("Access granted: user=%s, resource=%s, action=%s", user, resource, action)
+		s.sessions.touch(user, r)
+
 		next.ServeHTTP(w, r)
 	})
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	sendSuccess(w, map[string]string{
-		"status": "healthy",
+		"status":  "healthy",
 		"service": "casbin-rbac-example",
 	})
 }
@@ -175,6 +236,12 @@ func (s *Server) homeHandler(w http.ResponseWriter, r *http.Request) {
         <code>curl -X DELETE -H "X-User: admin_user" http://localhost:8080/api/documents/1</code>
     </div>
 
+    <div class="endpoint">
+        <strong>GET /api/me</strong><br>
+        Your profile, roles, permissions, sessions and pending access requests (requires user, manager, or admin role)<br>
+        <code>curl -H "X-User: bob" http://localhost:8080/api/me</code>
+    </div>
+
     <div class="endpoint">
         <strong>GET /api/policies</strong><br>
         View all policies (no auth required for demo)
@@ -209,7 +276,7 @@ func (s *Server) listDocumentsHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) createDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	var doc Document
 	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
-		sendError(w, http.StatusBadRequest, "Invalid request body")
+		sendError(w, r, http.StatusBadRequest, msgInvalidBody)
 		return
 	}
 
@@ -237,7 +304,7 @@ func (s *Server) getDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sendError(w, http.StatusNotFound, "Document not found")
+	sendError(w, r, http.StatusNotFound, msgDocumentNotFound)
 }
 
 func (s *Server) updateDocumentHandler(w http.ResponseWriter, r *http.Request) {
@@ -246,7 +313,7 @@ func (s *Server) updateDocumentHandler(w http.ResponseWriter, r *http.Request) {
 
 	var updates Document
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		sendError(w, http.StatusBadRequest, "Invalid request body")
+		sendError(w, r, http.StatusBadRequest, msgInvalidBody)
 		return
 	}
 
@@ -267,7 +334,7 @@ func (s *Server) updateDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sendError(w, http.StatusNotFound, "Document not found")
+	sendError(w, r, http.StatusNotFound, msgDocumentNotFound)
 }
 
 func (s *Server) deleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
@@ -285,7 +352,7 @@ func (s *Server) deleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sendError(w, http.StatusNotFound, "Document not found")
+	sendError(w, r, http.StatusNotFound, msgDocumentNotFound)
 }
 
 func (s *Server) listUsersHandler(w http.ResponseWriter, r *http.Request) {
@@ -311,10 +378,20 @@ func (s *Server) getUserPermissionsHandler(w http.ResponseWriter, r *http.Reques
 	user := vars["user"]
 
 	// Get implicit permissions for user (including inherited)
-	permissions := s.enforcer.GetImplicitPermissionsForUser(user)
+	permissions, err := s.authz.PermissionsForUser(user)
+	if err != nil {
+		log.Printf("Permission lookup failed: %v", err)
+		sendError(w, r, http.StatusInternalServerError, msgAuthzFailed)
+		return
+	}
 
 	// Get roles for user
-	roles := s.enforcer.GetRolesForUser(user)
+	roles, err := s.authz.RolesForUser(user)
+	if err != nil {
+		log.Printf("Role lookup failed: %v", err)
+		sendError(w, r, http.StatusInternalServerError, msgAuthzFailed)
+		return
+	}
 
 	result := map[string]interface{}{
 		"user":        user,
@@ -325,9 +402,46 @@ func (s *Server) getUserPermissionsHandler(w http.ResponseWriter, r *http.Reques
 	sendSuccess(w, result)
 }
 
+// getMeHandler returns everything about the calling user in one call,
+// so frontends need no admin-only endpoints to render a permissions page.
+func (s *Server) getMeHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Header.Get("X-User")
+
+	profile, ok := s.users[user]
+	if !ok {
+		profile = User{Username: user}
+	}
+
+	// Get implicit permissions for user (including inherited)
+	permissions, err := s.authz.PermissionsForUser(user)
+	if err != nil {
+		log.Printf("Permission lookup failed: %v", err)
+		sendError(w, r, http.StatusInternalServerError, msgAuthzFailed)
+		return
+	}
+
+	// Get roles for user (including inherited)
+	roles, err := s.authz.ImplicitRolesForUser(user)
+	if err != nil {
+		log.Printf("Role lookup failed: %v", err)
+		sendError(w, r, http.StatusInternalServerError, msgAuthzFailed)
+		return
+	}
+
+	result := map[string]interface{}{
+		"profile":                 profile,
+		"roles":                   roles,
+		"permissions":             permissions,
+		"sessions":                s.sessions.active(user),
+		"pending_access_requests": s.accessRequests.pending(user),
+	}
+
+	sendSuccess(w, result)
+}
+
 func (s *Server) listPoliciesHandler(w http.ResponseWriter, r *http.Request) {
-	policies := s.enforcer.GetPolicy()
-	grouping := s.enforcer.GetGroupingPolicy()
+	policies := s.authz.Policies()
+	grouping := s.authz.RoleAssignments()
 
 	result := map[string]interface{}{
 		"policies": policies,
@@ -338,6 +452,13 @@ func (s *Server) listPoliciesHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) addSampleData() {
+	s.users["alice"] = User{Username: "alice", Name: "Alice", Email: "alice@example.com"}
+	s.users["bob"] = User{Username: "bob", Name: "Bob", Email: "bob@example.com"}
+	s.users["charlie"] = User{Username: "charlie", Name: "Charlie", Email: "charlie@example.com"}
+	s.users["admin_user"] = User{Username: "admin_user", Name: "Admin", Email: "admin@example.com"}
+
+	s.accessRequests.add("bob", "manager", "Covering for Alice")
+
 	s.documents[1] = Document{
 		ID:      1,
 		Title:   "Getting Started Guide",
@@ -367,11 +488,17 @@ func sendSuccess(w http.ResponseWriter, data interface{}) {
 	})
 }
 
-func sendError(w http.ResponseWriter, status int, message string) {
+// sendError writes a localized error response. key is a message catalog
+// key (see i18n.go); the language is negotiated from Accept-Language.
+func sendError(w http.ResponseWriter, r *http.Request, status int, key string) {
+	locale, message := localize(r, key)
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(Response{
 		Success: false,
 		Error:   message,
+		Code:    key,
 	})
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func do(t *testing.T, s *Server, method, path, user string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-User", user)
+	req.Header.Set("User-Agent", "test-client")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetMe(t *testing.T) {
+	s := newTestServer(t)
+
+	if w := do(t, s, "GET", "/api/documents", "bob"); w.Code != http.StatusOK {
+		t.Fatalf("GET /api/documents as bob: status %d", w.Code)
+	}
+
+	w := do(t, s, "GET", "/api/me", "bob")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/me: status %d: %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Profile         User            `json:"profile"`
+			Roles           []string        `json:"roles"`
+			Permissions     [][]string      `json:"permissions"`
+			Sessions        []Session       `json:"sessions"`
+			PendingRequests []AccessRequest `json:"pending_access_requests"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	me := resp.Data
+
+	if me.Profile.Username != "bob" || me.Profile.Email != "bob@example.com" {
+		t.Errorf("profile = %+v", me.Profile)
+	}
+	if !reflect.DeepEqual(me.Roles, []string{"user"}) {
+		t.Errorf("roles = %v, want [user]", me.Roles)
+	}
+
+	wantPerm := []string{"user", "/api/me", "GET"}
+	found := false
+	for _, p := range me.Permissions {
+		found = found || reflect.DeepEqual(p, wantPerm)
+	}
+	if !found {
+		t.Errorf("permissions %v do not include %v", me.Permissions, wantPerm)
+	}
+
+	// One session from the test client, covering both requests
+	if len(me.Sessions) != 1 || me.Sessions[0].UserAgent != "test-client" {
+		t.Errorf("sessions = %+v, want one from test-client", me.Sessions)
+	}
+
+	if len(me.PendingRequests) != 1 || me.PendingRequests[0].Role != "manager" {
+		t.Errorf("pending requests = %+v, want one for manager", me.PendingRequests)
+	}
+}
+
+func TestGetMeInheritedRoles(t *testing.T) {
+	s := newTestServer(t)
+
+	w := do(t, s, "GET", "/api/me", "alice")
+	var resp struct {
+		Data struct {
+			Roles []string `json:"roles"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data.Roles, []string{"manager", "user"}) {
+		t.Errorf("roles = %v, want [manager user]", resp.Data.Roles)
+	}
+}
+
+func TestDeniedRequestStartsNoSession(t *testing.T) {
+	s := newTestServer(t)
+
+	if w := do(t, s, "DELETE", "/api/documents/1", "bob"); w.Code != http.StatusForbidden {
+		t.Fatalf("DELETE as bob: status %d, want 403", w.Code)
+	}
+	if w := do(t, s, "GET", "/api/documents", "mallory"); w.Code != http.StatusForbidden {
+		t.Fatalf("GET as unknown user: status %d, want 403", w.Code)
+	}
+
+	for _, user := range []string{"bob", "mallory"} {
+		if sessions := s.sessions.active(user); len(sessions) != 0 {
+			t.Errorf("%s has sessions after denied requests: %+v", user, sessions)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// sessionIdleTimeout is how long a session stays active without requests.
+	sessionIdleTimeout = 30 * time.Minute
+	// maxSessions bounds the number of tracked sessions across all users.
+	maxSessions = 10000
+)
+
+type Session struct {
+	ID        string    `json:"id"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// sessionTracker records the clients each user is active from. This demo
+// authenticates with a plain X-User header, so a session is identified by
+// user, client IP and user agent. Only requests that passed authorization
+// are recorded.
+//
+// Sessions are kept in a list ordered by last use, so idle sessions and,
+// when the tracker is full, the least recently used one are dropped from
+// its back without scanning.
+type sessionTracker struct {
+	mu       sync.Mutex
+	idle     time.Duration
+	max      int
+	sessions map[string]map[string]*list.Element // user -> session ID -> element in lru
+	lru      *list.List                          // of *trackedSession, most recent first
+}
+
+type trackedSession struct {
+	user    string
+	session Session
+}
+
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{
+		idle:     sessionIdleTimeout,
+		max:      maxSessions,
+		sessions: make(map[string]map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// touch records a request from user, starting a session if needed.
+func (t *sessionTracker) touch(user string, r *http.Request) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	agent := r.UserAgent()
+
+	sum := sha256.Sum256([]byte(user + "\x00" + ip + "\x00" + agent))
+	id := hex.EncodeToString(sum[:8])
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Read the clock under the lock so the list stays ordered by LastSeen
+	now := time.Now()
+	t.sweep(now)
+
+	if elem, ok := t.sessions[user][id]; ok {
+		elem.Value.(*trackedSession).session.LastSeen = now
+		t.lru.MoveToFront(elem)
+		return
+	}
+
+	if t.lru.Len() >= t.max {
+		t.remove(t.lru.Back())
+	}
+
+	byID := t.sessions[user]
+	if byID == nil {
+		byID = make(map[string]*list.Element)
+		t.sessions[user] = byID
+	}
+	byID[id] = t.lru.PushFront(&trackedSession{
+		user:    user,
+		session: Session{ID: id, ClientIP: ip, UserAgent: agent, StartedAt: now, LastSeen: now},
+	})
+}
+
+// active returns user's active sessions, most recent first.
+func (t *sessionTracker) active(user string) []Session {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := []Session{}
+	for _, elem := range t.sessions[user] {
+		session := elem.Value.(*trackedSession).session
+		if now.Sub(session.LastSeen) <= t.idle {
+			active = append(active, session)
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LastSeen.After(active[j].LastSeen)
+	})
+	return active
+}
+
+// sweep forgets idle sessions. Callers must hold mu.
+func (t *sessionTracker) sweep(now time.Time) {
+	for elem := t.lru.Back(); elem != nil; elem = t.lru.Back() {
+		if now.Sub(elem.Value.(*trackedSession).session.LastSeen) <= t.idle {
+			return
+		}
+		t.remove(elem)
+	}
+}
+
+// remove forgets the session held by elem. Callers must hold mu.
+func (t *sessionTracker) remove(elem *list.Element) {
+	tracked := t.lru.Remove(elem).(*trackedSession)
+	byID := t.sessions[tracked.user]
+	delete(byID, tracked.session.ID)
+	if len(byID) == 0 {
+		delete(t.sessions, tracked.user)
+	}
+}
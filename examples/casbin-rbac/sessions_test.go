@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func touchFrom(t *sessionTracker, user, agent string) {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("User-Agent", agent)
+	t.touch(user, req)
+}
+
+func TestSessionTrackerEvictsIdle(t *testing.T) {
+	tracker := newSessionTracker()
+	tracker.idle = 10 * time.Millisecond
+
+	touchFrom(tracker, "bob", "a")
+	touchFrom(tracker, "alice", "a")
+	time.Sleep(20 * time.Millisecond)
+
+	// Any write sweeps idle sessions
+	touchFrom(tracker, "charlie", "a")
+
+	if tracker.lru.Len() != 1 || len(tracker.sessions) != 1 {
+		t.Errorf("sessions = %d, users = %d; want only charlie's session", tracker.lru.Len(), len(tracker.sessions))
+	}
+	if sessions := tracker.active("bob"); len(sessions) != 0 {
+		t.Errorf("bob still has sessions: %+v", sessions)
+	}
+}
+
+func TestSessionTrackerLimit(t *testing.T) {
+	tracker := newSessionTracker()
+	tracker.max = 2
+
+	touchFrom(tracker, "bob", "first")
+	time.Sleep(time.Millisecond)
+	touchFrom(tracker, "bob", "second")
+	time.Sleep(time.Millisecond)
+	touchFrom(tracker, "bob", "third")
+
+	if tracker.lru.Len() != 2 {
+		t.Fatalf("sessions = %d, want 2", tracker.lru.Len())
+	}
+	sessions := tracker.active("bob")
+	if len(sessions) != 2 || sessions[0].UserAgent != "third" || sessions[1].UserAgent != "second" {
+		t.Errorf("sessions = %+v, want third and second", sessions)
+	}
+}
+
+func TestSessionTrackerReusesSession(t *testing.T) {
+	tracker := newSessionTracker()
+
+	touchFrom(tracker, "bob", "a")
+	touchFrom(tracker, "bob", "a")
+	touchFrom(tracker, "bob", "b")
+
+	if sessions := tracker.active("bob"); len(sessions) != 2 {
+		t.Errorf("got %d sessions, want 2", len(sessions))
+	}
+}
+
+func TestSessionTrackerEvictsLeastRecentlyUsed(t *testing.T) {
+	tracker := newSessionTracker()
+	tracker.max = 2
+
+	touchFrom(tracker, "bob", "a")
+	touchFrom(tracker, "alice", "a")
+	// Reusing bob's session makes alice's the least recently used
+	touchFrom(tracker, "bob", "a")
+	touchFrom(tracker, "charlie", "a")
+
+	if sessions := tracker.active("alice"); len(sessions) != 0 {
+		t.Errorf("alice still has sessions: %+v", sessions)
+	}
+	if _, ok := tracker.sessions["alice"]; ok {
+		t.Error("alice's empty session map was kept")
+	}
+	for _, user := range []string{"bob", "charlie"} {
+		if sessions := tracker.active(user); len(sessions) != 1 {
+			t.Errorf("%s has %d sessions, want 1", user, len(sessions))
+		}
+	}
+}